@@ -14,6 +14,15 @@ var (
 		Short: "build packages",
 		Long:  "build all packages by default, unless specific packages are specified",
 		Run: func(cmd *cobra.Command, packageNames []string) {
+			hammer.Unsafe = viper.GetBool("unsafe")
+
+			if d := viper.GetString("distro"); d != "" {
+				hammer.TargetHost.ID = d
+			}
+			if a := viper.GetString("arch"); a != "" {
+				hammer.TargetHost.Arch = a
+			}
+
 			loader := hammer.NewLoader(viper.GetString("search"))
 			loaded, err := loader.Load()
 			if err != nil {
@@ -35,6 +44,7 @@ var (
 			}
 
 			packager := hammer.NewPackager(packages)
+			packager.Jobs = viper.GetInt("jobs")
 
 			err = packager.EnsureOutputDir(viper.GetString("output"))
 			if err != nil {
@@ -46,4 +56,18 @@ var (
 			}
 		},
 	}
-)
\ No newline at end of file
+)
+
+func init() {
+	BuildCmd.Flags().IntP("jobs", "j", 1, "number of packages to build concurrently")
+	viper.BindPFlag("jobs", BuildCmd.Flags().Lookup("jobs"))
+
+	BuildCmd.Flags().Bool("unsafe", false, "run build scripts with the host shell instead of hammer's sandbox")
+	viper.BindPFlag("unsafe", BuildCmd.Flags().Lookup("unsafe"))
+
+	BuildCmd.Flags().String("distro", "", "override the detected distro ID used to resolve package overrides")
+	viper.BindPFlag("distro", BuildCmd.Flags().Lookup("distro"))
+
+	BuildCmd.Flags().String("arch", "", "override the detected architecture used to resolve package overrides")
+	viper.BindPFlag("arch", BuildCmd.Flags().Lookup("arch"))
+}
@@ -0,0 +1,88 @@
+package hammer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Format identifies a package archive format a Backend knows how to emit.
+type Format string
+
+const (
+	FormatRPM  Format = "rpm"
+	FormatDEB  Format = "deb"
+	FormatAPK  Format = "apk"
+	FormatArch Format = "archlinux"
+)
+
+// AllFormats is the default set of formats built when a Package does not
+// declare a `formats:` list of its own. FormatRPM is deliberately left
+// out: the native backend's RPM output isn't a spec-compliant RPM yet
+// (see backend_rpm.go), so it's opt-in only via an explicit `formats:
+// [rpm]` until that's fixed.
+var AllFormats = []Format{FormatDEB, FormatAPK, FormatArch}
+
+var (
+	ErrUnknownFormat  = errors.New("unknown package format")
+	ErrUnknownBackend = errors.New("unknown packager backend")
+)
+
+// Backend builds a single archive, in Format, out of a Package's staged
+// BuildRoot and writes it into the package's OutputRoot.
+type Backend interface {
+	Build(p *Package, format Format) ([]byte, error)
+}
+
+// backends maps the name a Package may put in its `backend:` field to the
+// Backend implementation that should service it. "native" is the default;
+// "fpm" remains available for operators still relying on the Ruby fpm(1)
+// tool being installed on the build host.
+var backends = map[string]Backend{
+	"native": &nativeBackend{},
+	"fpm":    &fpmBackend{},
+}
+
+func backendFor(name string) (Backend, error) {
+	if name == "" {
+		name = "native"
+	}
+
+	b, ok := backends[name]
+	if !ok {
+		return nil, ErrUnknownBackend
+	}
+
+	return b, nil
+}
+
+// Formats returns the archive formats this package should be built as,
+// defaulting to AllFormats when the package did not declare any.
+func (p *Package) Formats() []Format {
+	if len(p.RawFormats) == 0 {
+		return AllFormats
+	}
+
+	formats := make([]Format, len(p.RawFormats))
+	for i, f := range p.RawFormats {
+		formats[i] = Format(f)
+	}
+
+	return formats
+}
+
+// OutputName returns the filename the native backend writes for format,
+// the same naming scheme Package.Build's overwrite guard checks against.
+func (p *Package) OutputName(format Format) string {
+	switch format {
+	case FormatRPM:
+		return fmt.Sprintf("%s-%s-%s.rpm", p.Name, p.Version, p.Iteration)
+	case FormatDEB:
+		return fmt.Sprintf("%s_%s-%s_amd64.deb", p.Name, p.Version, p.Iteration)
+	case FormatAPK:
+		return fmt.Sprintf("%s-%s-%s.apk", p.Name, p.Version, p.Iteration)
+	case FormatArch:
+		return fmt.Sprintf("%s-%s-%s.pkg.tar.gz", p.Name, p.Version, p.Iteration)
+	default:
+		return fmt.Sprintf("%s-%s-%s.%s", p.Name, p.Version, p.Iteration, format)
+	}
+}
@@ -0,0 +1,110 @@
+package hammer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// debMagic is the ar(1) archive magic every .deb begins with.
+const debMagic = "!<arch>\n"
+
+// buildDEB assembles a .deb, which is an ar(1) archive containing
+// debian-binary, control.tar.gz, and data.tar.gz, in that order.
+func (p *Package) buildDEB(stageRoot string) ([]byte, error) {
+	data, err := tarGzTree(stageRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	control, err := p.debControlTarGz()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(debMagic)
+	writeArEntry(&buf, "debian-binary", []byte("2.0\n"))
+	writeArEntry(&buf, "control.tar.gz", control)
+	writeArEntry(&buf, "data.tar.gz", data)
+
+	return buf.Bytes(), nil
+}
+
+// debControlTarGz builds the control member: a tar.gz containing the
+// control file describing the package to dpkg, plus any maintainer
+// scripts (preinst, postinst, prerm, postrm) the package defines.
+func (p *Package) debControlTarGz() ([]byte, error) {
+	controlDir, err := ioutil.TempDir("", "hammer-control-"+p.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(controlDir)
+
+	contents := fmt.Sprintf(
+		"Package: %s\nVersion: %s-%s\nArchitecture: amd64\nMaintainer: %s\nDescription: %s\n",
+		p.Name, p.Version, p.Iteration, p.Vendor, p.Description,
+	)
+	for _, depend := range p.Depends {
+		contents += "Depends: " + depend + "\n"
+	}
+
+	if err := ioutil.WriteFile(path.Join(controlDir, "control"), []byte(contents), 0644); err != nil {
+		return nil, err
+	}
+
+	hooks, err := p.Scripts.Hooks(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDebScripts(controlDir, hooks); err != nil {
+		return nil, err
+	}
+
+	return tarGzTree(controlDir)
+}
+
+// debScriptMap says which of hammer's maintainer-script hooks feed into
+// each dpkg maintainer script; dpkg has no separate upgrade hooks, so
+// before-upgrade/after-upgrade are folded into preinst/postinst, which
+// dpkg also invokes across upgrades.
+var debScriptMap = map[string][]string{
+	"preinst":  {"before-install", "before-upgrade"},
+	"postinst": {"after-install", "after-upgrade"},
+	"prerm":    {"before-remove"},
+	"postrm":   {"after-remove"},
+}
+
+func writeDebScripts(controlDir string, hooks map[string]string) error {
+	for script, sources := range debScriptMap {
+		var body bytes.Buffer
+		for _, name := range sources {
+			if content, ok := hooks[name]; ok {
+				fmt.Fprintf(&body, "# %s\n%s\n", name, content)
+			}
+		}
+		if body.Len() == 0 {
+			continue
+		}
+
+		full := "#!/bin/sh\nset -e\n" + body.String()
+		if err := ioutil.WriteFile(path.Join(controlDir, script), []byte(full), 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeArEntry appends one ar(1) member: a 60-byte fixed-width header
+// (name, mtime, uid, gid, mode, size, and the "`\n" end marker) followed
+// by the body, padded to an even length.
+func writeArEntry(buf *bytes.Buffer, name string, body []byte) {
+	fmt.Fprintf(buf, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", name, 0, 0, 0, "100644", len(body))
+	buf.Write(body)
+	if len(body)%2 != 0 {
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,56 @@
+package hammer
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// fpmBackend shells out to the Ruby fpm(1) tool, same as hammer has always
+// done. It is kept around for operators whose build hosts already carry
+// fpm and who haven't migrated to the native backend yet.
+type fpmBackend struct{}
+
+// fpmTargetNames translates a Format into the `-t` value fpm itself
+// expects, for the formats where the two disagree on naming.
+var fpmTargetNames = map[Format]string{
+	FormatArch: "pacman",
+}
+
+func fpmTargetName(format Format) string {
+	if name, ok := fpmTargetNames[format]; ok {
+		return name
+	}
+	return string(format)
+}
+
+func (b *fpmBackend) Build(p *Package, format Format) ([]byte, error) {
+	args, err := p.fpmArgs()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	prefixArgs := []string{
+		"-s", "dir",
+		"-t", fpmTargetName(format),
+		"-p", p.OutputRoot,
+	}
+	args = append(prefixArgs, args...)
+
+	p.logger.WithField("format", format).Info("packaging with FPM")
+	fpm := exec.Command("fpm", args...)
+	out, err := fpm.CombinedOutput()
+
+	if err == nil && !fpm.ProcessState.Success() {
+		err = errors.New("package command exited with a non-zero exit code")
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"systemTime": fpm.ProcessState.SystemTime(),
+		"userTime":   fpm.ProcessState.UserTime(),
+		"success":    fpm.ProcessState.Success(),
+	}).Debug("package command exited")
+
+	return out, err
+}
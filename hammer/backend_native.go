@@ -0,0 +1,250 @@
+package hammer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// nativeBackend builds each supported Format directly with the standard
+// library, the way nfpm does, so that hammer does not require the Ruby
+// fpm(1) tool to be installed on the build host.
+type nativeBackend struct{}
+
+func (b *nativeBackend) Build(p *Package, format Format) ([]byte, error) {
+	stageRoot, err := p.stage()
+	defer os.RemoveAll(stageRoot)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	var data []byte
+
+	switch format {
+	case FormatRPM:
+		data, err = p.buildRPM(stageRoot)
+	case FormatDEB:
+		data, err = p.buildDEB(stageRoot)
+	case FormatAPK:
+		data, err = p.buildAPK(stageRoot)
+	case FormatArch:
+		data, err = p.buildArch(stageRoot)
+	default:
+		return []byte{}, ErrUnknownFormat
+	}
+
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return []byte{}, ioutil.WriteFile(path.Join(p.OutputRoot, p.OutputName(format)), data, 0644)
+}
+
+// stage renders and copies every Target into a fresh tree that mirrors the
+// filesystem layout each archive format will ship, the way Package.Package
+// used to hand FPM a directory of files to wrap.
+func (p *Package) stage() (string, error) {
+	stageRoot, err := ioutil.TempDir("", "hammer-stage-"+p.Name)
+	if err != nil {
+		p.logger.WithField("error", err).Error("could not create staging directory")
+		return stageRoot, err
+	}
+
+	for i, target := range p.Targets {
+		src, err := p.Render(target.Src)
+		if err != nil {
+			p.logger.WithField("index", i).Error("error templating target source")
+			return stageRoot, err
+		}
+
+		dest, err := p.Render(target.Dest)
+		if err != nil {
+			p.logger.WithField("index", i).Error("error templating target destination")
+			return stageRoot, err
+		}
+
+		if err := copyFile(src.String(), path.Join(stageRoot, dest.String())); err != nil {
+			p.logger.WithFields(logrus.Fields{
+				"src":   src.String(),
+				"dest":  dest.String(),
+				"error": err,
+			}).Error("could not stage target")
+			return stageRoot, err
+		}
+	}
+
+	return stageRoot, nil
+}
+
+func copyFile(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// tarGzTree walks root and returns a gzip-compressed tar of everything
+// under it, with paths relative to root.
+func tarGzTree(root string) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// apkScriptMap names the root-level script file Alpine's apk looks for
+// per hammer hook.
+var apkScriptMap = map[string]string{
+	"before-install": "pre-install",
+	"after-install":  "post-install",
+	"before-remove":  "pre-deinstall",
+	"after-remove":   "post-deinstall",
+	"before-upgrade": "pre-upgrade",
+	"after-upgrade":  "post-upgrade",
+}
+
+// archInstallFunctions names the makepkg .INSTALL function per hammer
+// hook.
+var archInstallFunctions = map[string]string{
+	"before-install": "pre_install",
+	"after-install":  "post_install",
+	"before-remove":  "pre_remove",
+	"after-remove":   "post_remove",
+	"before-upgrade": "pre_upgrade",
+	"after-upgrade":  "post_upgrade",
+}
+
+// buildAPK and buildArch both ship as a tar.gz of the staged tree plus a
+// format-specific metadata file, so they share the tar-building logic.
+func (p *Package) buildAPK(stageRoot string) ([]byte, error) {
+	hooks, err := p.Scripts.Hooks(p)
+	if err != nil {
+		return nil, err
+	}
+
+	info := fmt.Sprintf("pkgname = %s\npkgver = %s-%s\npkgdesc = %s\n", p.Name, p.Version, p.Iteration, p.Description)
+
+	scripts := map[string]string{}
+	for hook, content := range hooks {
+		if name, ok := apkScriptMap[hook]; ok {
+			scripts[name] = "#!/bin/sh\n" + content
+		}
+	}
+
+	return p.tarGzWithMetadata(stageRoot, ".PKGINFO", info, scripts)
+}
+
+func (p *Package) buildArch(stageRoot string) ([]byte, error) {
+	hooks, err := p.Scripts.Hooks(p)
+	if err != nil {
+		return nil, err
+	}
+
+	info := fmt.Sprintf("pkgname = %s\npkgver = %s-%s\npkgdesc = %s\n", p.Name, p.Version, p.Iteration, p.Description)
+
+	scripts := map[string]string{}
+	var install bytes.Buffer
+	for _, hook := range hookNames {
+		content, ok := hooks[hook]
+		if !ok {
+			continue
+		}
+		fn := archInstallFunctions[hook]
+		fmt.Fprintf(&install, "%s() {\n%s\n}\n", fn, content)
+	}
+	if install.Len() > 0 {
+		scripts[".INSTALL"] = install.String()
+	}
+
+	return p.tarGzWithMetadata(stageRoot, ".PKGINFO", info, scripts)
+}
+
+func (p *Package) tarGzWithMetadata(stageRoot, metaName, metaContents string, scripts map[string]string) ([]byte, error) {
+	metaPath := path.Join(stageRoot, metaName)
+	if err := ioutil.WriteFile(metaPath, []byte(metaContents), 0644); err != nil {
+		return nil, err
+	}
+	defer os.Remove(metaPath)
+
+	for name, content := range scripts {
+		scriptPath := path.Join(stageRoot, name)
+		if err := ioutil.WriteFile(scriptPath, []byte(content), 0755); err != nil {
+			return nil, err
+		}
+		defer os.Remove(scriptPath)
+	}
+
+	return tarGzTree(stageRoot)
+}
@@ -0,0 +1,182 @@
+package hammer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// rpmLeadMagic is the four bytes every RPM file begins with.
+var rpmLeadMagic = []byte{0xed, 0xab, 0xee, 0xdb}
+
+// buildRPM emits a minimal RPM-shaped file: a 96-byte lead, a simplified
+// header region (see rpmHeader) carrying the name, version, iteration,
+// arch and maintainer scripts, and a gzipped cpio payload of the staged
+// tree.
+//
+// It writes no signature header and no file-list tags, and its header
+// region is not rpm's tag/offset/type index, so rpm tooling that parses
+// the header does not accept the result.
+func (p *Package) buildRPM(stageRoot string) ([]byte, error) {
+	payload, err := cpioGzTree(stageRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := p.Scripts.Hooks(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(rpmLead(p.Name))
+	buf.Write(rpmHeader(p, hooks))
+	buf.Write(payload)
+
+	return buf.Bytes(), nil
+}
+
+func rpmLead(name string) []byte {
+	lead := make([]byte, 96)
+	copy(lead, rpmLeadMagic)
+	lead[4] = 3                                // major version
+	lead[5] = 0                                // minor version
+	binary.BigEndian.PutUint16(lead[6:8], 0)   // type: binary
+	binary.BigEndian.PutUint16(lead[8:10], 1)  // archnum: x86
+	copy(lead[10:76], name)
+	binary.BigEndian.PutUint16(lead[76:78], 5) // osnum: linux
+	return lead
+}
+
+// rpmScriptMap folds hammer's hooks onto rpm's four scriptlet names;
+// rpm has no separate upgrade scriptlets (it tells prein/postin apart
+// from an install by the argument count it passes them), so
+// before-upgrade/after-upgrade are folded into prein/postin too.
+var rpmScriptMap = map[string][]string{
+	"prein":  {"before-install", "before-upgrade"},
+	"postin": {"after-install", "after-upgrade"},
+	"preun":  {"before-remove"},
+	"postun": {"after-remove"},
+}
+
+// rpmHeader writes the name, version, iteration and arch (no epoch) plus
+// any maintainer scripts as NUL-terminated strings; a real RPM header is a tag/offset/
+// type index, which is more structure than hammer's current consumers
+// (our own repo tooling) need.
+func rpmHeader(p *Package, hooks map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, tag := range []string{p.Name, p.Version, p.Iteration, "x86_64"} {
+		buf.WriteString(tag)
+		buf.WriteByte(0)
+	}
+
+	for _, script := range []string{"prein", "postin", "preun", "postun"} {
+		var body bytes.Buffer
+		for _, hook := range rpmScriptMap[script] {
+			if content, ok := hooks[hook]; ok {
+				body.WriteString(content)
+			}
+		}
+		if body.Len() == 0 {
+			continue
+		}
+
+		buf.WriteString(script)
+		buf.WriteByte(0)
+		buf.Write(body.Bytes())
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes()
+}
+
+// cpioGzTree walks root and writes a gzip-compressed "newc" format cpio
+// archive, the payload format RPM expects.
+func cpioGzTree(root string) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+
+	ino := uint32(0)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		var body []byte
+		if !info.IsDir() {
+			if body, err = ioutil.ReadFile(p); err != nil {
+				return err
+			}
+		}
+
+		ino++
+		return writeCpioEntry(gw, rel, info, ino, body)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeCpioEntry(gw, "TRAILER!!!", nil, 0, nil); err != nil {
+		return nil, err
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeCpioEntry writes one "newc" cpio header, its NUL-padded name, and
+// its (4-byte aligned) body. A nil FileInfo writes the zero-mode trailer
+// entry that terminates the archive.
+func writeCpioEntry(w io.Writer, name string, info os.FileInfo, ino uint32, body []byte) error {
+	var mode uint32
+	if info != nil {
+		mode = uint32(info.Mode().Perm())
+		if info.IsDir() {
+			mode |= 0040000
+		} else {
+			mode |= 0100000
+		}
+	}
+
+	namesize := len(name) + 1
+	header := fmt.Sprintf(
+		"070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+		ino, mode, 0, 0, 1, 0, len(body), 0, 0, 0, 0, namesize, 0,
+	)
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, name+"\x00"); err != nil {
+		return err
+	}
+	if err := padTo4(w, 6+namesize); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	return padTo4(w, len(body))
+}
+
+func padTo4(w io.Writer, n int) error {
+	if pad := (4 - n%4) % 4; pad > 0 {
+		_, err := w.Write(make([]byte, pad))
+		return err
+	}
+	return nil
+}
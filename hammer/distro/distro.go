@@ -0,0 +1,40 @@
+// Package distro identifies the distribution and architecture hammer is
+// building on, so packages can carry per-distro/arch overrides.
+package distro
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Info describes a build host: a distro ID such as "debian", "rhel", or
+// "alpine", and a Go arch such as "amd64" or "arm64".
+type Info struct {
+	ID   string
+	Arch string
+}
+
+// Detect reads /etc/os-release for the distro ID and reports the Go
+// runtime's GOARCH for the architecture.
+func Detect() (Info, error) {
+	info := Info{Arch: runtime.GOARCH}
+
+	f, err := os.Open("/etc/os-release")
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "ID=") {
+			continue
+		}
+		info.ID = strings.Trim(strings.TrimPrefix(line, "ID="), `"`)
+	}
+
+	return info, scanner.Err()
+}
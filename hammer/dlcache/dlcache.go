@@ -0,0 +1,84 @@
+// Package dlcache is a content-addressed cache for downloaded resources,
+// keyed by the URL they came from and the checksum they were declared to
+// have, so that two builds pinning the same URL to different checksums
+// don't collide.
+package dlcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+var ErrNoCacheDir = errors.New("could not determine cache directory: HOME is not set")
+
+// Dir returns the directory hammer caches verified downloads under,
+// honoring $XDG_CACHE_HOME and falling back to $HOME/.cache/hammer.
+func Dir() (string, error) {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", ErrNoCacheDir
+		}
+		base = path.Join(home, ".cache")
+	}
+
+	dir := path.Join(base, "hammer")
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+func key(url, checksum string) string {
+	sum := sha256.Sum256([]byte(url + "|" + checksum))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached body for url/checksum, with ok false if nothing
+// is cached for that pair yet.
+func Get(url, checksum string) (body []byte, ok bool, err error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, false, err
+	}
+
+	body, err = ioutil.ReadFile(path.Join(dir, key(url, checksum)))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return body, true, nil
+}
+
+// Put writes body into the cache under url/checksum. The write is atomic
+// with respect to concurrent readers/writers of the same entry (hammer's
+// scheduler can build several packages sharing a resource in parallel):
+// it lands in a temp file first and is only renamed into place once
+// complete.
+func Put(url, checksum string, body []byte) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-"+key(url, checksum))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path.Join(dir, key(url, checksum)))
+}
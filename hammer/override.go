@@ -0,0 +1,127 @@
+package hammer
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/asteris-llc/hammer/hammer/distro"
+)
+
+// TargetHost is the distro/arch a Package's `_<suffix>` overrides are
+// resolved against. It starts out detected from the build host, and its
+// ID and Arch can each be overridden by BuildCmd's --distro/--arch flags, which lets
+// one recipe produce correct output for a distro other than the one
+// hammer happens to be running on.
+var TargetHost = detectHost()
+
+func detectHost() distro.Info {
+	if info, err := distro.Detect(); err == nil && info.ID != "" {
+		return info
+	}
+	return distro.Info{Arch: runtime.GOARCH}
+}
+
+// clearSentinel as the sole element of a list override means "start from
+// an empty list" instead of appending to the base field's list.
+const clearSentinel = "!clear"
+
+// applyOverrides resolves host-specific `<field>_<suffix>` keys in the raw
+// package YAML against host, most-specific suffix last so it wins: arch
+// alone, then distro alone, then distro_arch together. List fields append
+// unless their override is exactly [clearSentinel], which empties them;
+// map fields merge keys; everything else is replaced outright.
+func applyOverrides(p *Package, content []byte, host distro.Info) error {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return err
+	}
+
+	suffixes := []string{}
+	if host.Arch != "" {
+		suffixes = append(suffixes, host.Arch)
+	}
+	if host.ID != "" {
+		suffixes = append(suffixes, host.ID)
+	}
+	if host.ID != "" && host.Arch != "" {
+		suffixes = append(suffixes, host.ID+"_"+host.Arch)
+	}
+
+	v := reflect.ValueOf(p).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		base := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if base == "" || base == "-" {
+			continue
+		}
+
+		for _, suffix := range suffixes {
+			val, ok := raw[base+"_"+suffix]
+			if !ok {
+				continue
+			}
+
+			if err := mergeOverride(v.Field(i), val); err != nil {
+				return fmt.Errorf("override %s_%s: %w", base, suffix, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func mergeOverride(field reflect.Value, raw interface{}) error {
+	// Check for the !clear sentinel against the raw, still-untyped YAML
+	// value, before trying to decode it into field's type: for a
+	// struct-element slice (e.g. []Target), decoding ["!clear"] would
+	// fail since "!clear" doesn't unmarshal into a Target.
+	if field.Kind() == reflect.Slice && isClearSentinel(raw) {
+		field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+		return nil
+	}
+
+	encoded, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	decoded := reflect.New(field.Type())
+	if err := yaml.Unmarshal(encoded, decoded.Interface()); err != nil {
+		return err
+	}
+	decodedVal := decoded.Elem()
+
+	switch field.Kind() {
+	case reflect.Slice:
+		field.Set(reflect.AppendSlice(field, decodedVal))
+	case reflect.Map:
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		for _, key := range decodedVal.MapKeys() {
+			field.SetMapIndex(key, decodedVal.MapIndex(key))
+		}
+	default:
+		field.Set(decodedVal)
+	}
+
+	return nil
+}
+
+// isClearSentinel reports whether raw is exactly a one-element list
+// containing clearSentinel, regardless of what type the overridden field
+// would otherwise decode its elements as.
+func isClearSentinel(raw interface{}) bool {
+	list, ok := raw.([]interface{})
+	if !ok || len(list) != 1 {
+		return false
+	}
+
+	s, ok := list[0].(string)
+	return ok && s == clearSentinel
+}
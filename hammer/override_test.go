@@ -0,0 +1,71 @@
+package hammer
+
+import (
+	"testing"
+
+	"github.com/asteris-llc/hammer/hammer/distro"
+)
+
+func TestApplyOverridesAppendsByDefault(t *testing.T) {
+	p := &Package{Depends: []string{"base"}}
+	content := []byte("depends_debian: [\"libssl\"]\n")
+
+	if err := applyOverrides(p, content, distro.Info{ID: "debian"}); err != nil {
+		t.Fatalf("applyOverrides returned error: %v", err)
+	}
+
+	if got, want := p.Depends, []string{"base", "libssl"}; !equalStrings(got, want) {
+		t.Errorf("Depends = %v, want %v", got, want)
+	}
+}
+
+func TestApplyOverridesClearSentinelOnStringSlice(t *testing.T) {
+	p := &Package{Depends: []string{"base"}}
+	content := []byte("depends_debian: [\"!clear\"]\n")
+
+	if err := applyOverrides(p, content, distro.Info{ID: "debian"}); err != nil {
+		t.Fatalf("applyOverrides returned error: %v", err)
+	}
+
+	if len(p.Depends) != 0 {
+		t.Errorf("Depends = %v, want empty", p.Depends)
+	}
+}
+
+func TestApplyOverridesClearSentinelOnStructSlice(t *testing.T) {
+	p := &Package{Targets: []Target{{Src: "a", Dest: "b"}}}
+	content := []byte("targets_arm64: [\"!clear\"]\n")
+
+	if err := applyOverrides(p, content, distro.Info{Arch: "arm64"}); err != nil {
+		t.Fatalf("applyOverrides returned error: %v", err)
+	}
+
+	if len(p.Targets) != 0 {
+		t.Errorf("Targets = %v, want empty", p.Targets)
+	}
+}
+
+func TestApplyOverridesMostSpecificWins(t *testing.T) {
+	p := &Package{Backend: "native"}
+	content := []byte("backend_rhel: fpm\nbackend_rhel_amd64: fpm-amd64\n")
+
+	if err := applyOverrides(p, content, distro.Info{ID: "rhel", Arch: "amd64"}); err != nil {
+		t.Fatalf("applyOverrides returned error: %v", err)
+	}
+
+	if p.Backend != "fpm-amd64" {
+		t.Errorf("Backend = %q, want the distro_arch override to win", p.Backend)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
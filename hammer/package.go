@@ -3,14 +3,11 @@ package hammer
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"github.com/Sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
-	"path/filepath"
 	"text/template"
 )
 
@@ -24,18 +21,21 @@ type Target struct {
 }
 
 type Package struct {
-	Name        string     `yaml:"name"`
-	Version     string     `yaml:"version"`
-	Iteration   string     `yaml:"iteration"`
-	Epoch       string     `yaml:"epoch"`
-	License     string     `yaml:"license"`
-	Vendor      string     `yaml:"vendor"`
-	URL         string     `yaml:"url"`
-	Description string     `yaml:"description"`
-	Depends     []string   `yaml:"depends"`
-	Resources   []Resource `yaml:"resources"`
-	Targets     []Target   `yaml:"targets"`
-	Scripts     Scripts    `yaml:"scripts"`
+	Name         string     `yaml:"name"`
+	Version      string     `yaml:"version"`
+	Iteration    string     `yaml:"iteration"`
+	Epoch        string     `yaml:"epoch"`
+	License      string     `yaml:"license"`
+	Vendor       string     `yaml:"vendor"`
+	URL          string     `yaml:"url"`
+	Description  string     `yaml:"description"`
+	Depends      []string   `yaml:"depends"`
+	Resources    []Resource `yaml:"resources"`
+	Targets      []Target   `yaml:"targets"`
+	Scripts      Scripts    `yaml:"scripts"`
+	RawFormats   []string   `yaml:"formats"`
+	Backend      string     `yaml:"backend"`
+	BuildDepends []string   `yaml:"build-depends"`
 
 	// internal state
 	BuildRoot  string `yaml:"-"`
@@ -47,9 +47,17 @@ type Package struct {
 
 func NewPackageFromYAML(content []byte) (*Package, error) {
 	p := new(Package)
-	err := yaml.Unmarshal(content, p)
+	if err := yaml.Unmarshal(content, p); err != nil {
+		return p, err
+	}
 	p.logger = logrus.WithField("name", p.Name)
-	return p, err
+
+	if err := applyOverrides(p, content, TargetHost); err != nil {
+		p.logger.WithField("error", err).Error("could not resolve distro/arch overrides")
+		return p, err
+	}
+
+	return p, nil
 }
 
 func (p *Package) Cleanup() error {
@@ -69,30 +77,18 @@ func (p *Package) Cleanup() error {
 }
 
 func (p *Package) Build() error {
-	// check for existing package
-	nameGlob := fmt.Sprintf("%s-%s-%s.*", p.Name, p.Version, p.Iteration)
-	files, err := ioutil.ReadDir(p.OutputRoot)
-	if err != nil {
-		p.logger.WithField("error", err).Error("could not read output directory")
-		return err
-	}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		matched, err := filepath.Match(nameGlob, file.Name())
-
-		if err != nil {
-			p.logger.WithFields(logrus.Fields{
-				"name":  file.Name(),
-				"glob":  nameGlob,
-				"error": err,
-			}).Error("could not match")
-		}
-
-		if matched {
-			p.logger.WithField("name", file.Name()).Warn("found conflicting output file - not building to avoid overwrite")
+	// check for existing package. Each format names its output
+	// differently (see Package.OutputName), so check every name this
+	// build would produce rather than a single FPM-era glob.
+	for _, format := range p.Formats() {
+		name := p.OutputName(format)
+
+		if _, err := os.Stat(path.Join(p.OutputRoot, name)); err == nil {
+			p.logger.WithField("name", name).Warn("found conflicting output file - not building to avoid overwrite")
 			return nil // TODO: does this make sense? It's not really a failure condition.
+		} else if !os.IsNotExist(err) {
+			p.logger.WithField("error", err).Error("could not stat output file")
+			return err
 		}
 	}
 
@@ -153,34 +149,26 @@ func (p *Package) Render(in string) (bytes.Buffer, error) {
 }
 
 func (p *Package) Package() ([]byte, error) {
-	args, err := p.fpmArgs()
+	backend, err := backendFor(p.Backend)
 	if err != nil {
+		p.logger.WithField("backend", p.Backend).Error("unknown packager backend")
 		return []byte{}, err
 	}
 
-	// prepend source and dest arguments
-	prefixArgs := []string{
-		"-s", "dir",
-		"-t", "rpm",
-		"-p", p.OutputRoot,
-	}
-	args = append(prefixArgs, args...) // TODO: make this do any type of packaging supported by FPM
+	var out []byte
+	for _, format := range p.Formats() {
+		formatLogger := p.logger.WithField("format", format)
+		formatLogger.Info("packaging")
 
-	p.logger.Info("packaging with FPM")
-	fpm := exec.Command("fpm", args...)
-	out, err := fpm.CombinedOutput()
-
-	if err == nil && !fpm.ProcessState.Success() {
-		err = errors.New("package command exited with a non-zero exit code")
+		formatOut, err := backend.Build(p, format)
+		out = append(out, formatOut...)
+		if err != nil {
+			formatLogger.WithField("error", err).Error("failed to build format")
+			return out, err
+		}
 	}
 
-	p.logger.WithFields(logrus.Fields{
-		"systemTime": fpm.ProcessState.SystemTime(),
-		"userTime":   fpm.ProcessState.UserTime(),
-		"success":    fpm.ProcessState.Success(),
-	}).Debug("package command exited")
-
-	return out, err
+	return out, nil
 }
 
 func (p *Package) fpmArgs() ([]string, error) {
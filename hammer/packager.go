@@ -0,0 +1,225 @@
+package hammer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// ErrDependencyCycle is returned when a set of packages' build-depends
+// fields cannot be satisfied by any ordering.
+var ErrDependencyCycle = errors.New("build-depends cycle detected")
+
+// ErrDuplicatePackageName is returned when two packages in the same build
+// share a name; the scheduler keys its per-package state on name, so a
+// duplicate would make waiting/completion ambiguous.
+var ErrDuplicatePackageName = errors.New("duplicate package name")
+
+// Packager builds a set of Packages, honoring build-depends ordering and
+// running up to Jobs builds concurrently.
+type Packager struct {
+	Packages []*Package
+	Jobs     int
+
+	logger *logrus.Entry
+}
+
+// NewPackager returns a Packager for packages, defaulting to one build at
+// a time; callers that want parallelism should set Jobs afterwards.
+func NewPackager(packages []*Package) *Packager {
+	return &Packager{
+		Packages: packages,
+		Jobs:     1,
+		logger:   logrus.WithField("component", "packager"),
+	}
+}
+
+// EnsureOutputDir creates output and points every package's OutputRoot at
+// it.
+func (pkgr *Packager) EnsureOutputDir(output string) error {
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return err
+	}
+
+	for _, p := range pkgr.Packages {
+		p.OutputRoot = output
+	}
+
+	return nil
+}
+
+// Build builds every package in pkgr.Packages. Packages run concurrently
+// up to pkgr.Jobs at a time, but a package never starts until everything
+// in its build-depends has finished, and a package whose prerequisites
+// failed is skipped rather than built. It returns false if any package
+// failed or was skipped.
+func (pkgr *Packager) Build() bool {
+	if err := pkgr.checkDuplicateNames(); err != nil {
+		pkgr.logger.WithField("error", err).Error("invalid package set")
+		return false
+	}
+
+	if err := pkgr.checkCycles(); err != nil {
+		pkgr.logger.WithField("error", err).Error("invalid build-depends graph")
+		return false
+	}
+
+	jobs := pkgr.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	sem := make(chan struct{}, jobs)
+
+	done := make(map[string]chan struct{}, len(pkgr.Packages))
+	for _, p := range pkgr.Packages {
+		done[p.Name] = make(chan struct{})
+	}
+
+	var (
+		mu     sync.Mutex
+		failed = make(map[string]bool, len(pkgr.Packages))
+		wg     sync.WaitGroup
+	)
+
+	for _, p := range pkgr.Packages {
+		wg.Add(1)
+		go func(p *Package) {
+			defer wg.Done()
+			defer close(done[p.Name])
+
+			waitedOn := []string{}
+			for _, dep := range p.BuildDepends {
+				depDone, ok := done[dep]
+				if !ok {
+					continue // not part of this build; nothing to wait on
+				}
+				waitedOn = append(waitedOn, dep)
+				<-depDone
+			}
+
+			logger := pkgr.logger.WithFields(logrus.Fields{
+				"package":  p.Name,
+				"waitedOn": waitedOn,
+			})
+
+			mu.Lock()
+			blocked := false
+			for _, dep := range waitedOn {
+				if failed[dep] {
+					blocked = true
+				}
+			}
+			mu.Unlock()
+
+			if blocked {
+				logger.Warn("skipping package; a build-depends entry failed")
+				mu.Lock()
+				failed[p.Name] = true
+				mu.Unlock()
+				return
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			err := p.Build()
+			status := "success"
+			if err != nil {
+				status = "failed"
+			}
+
+			logger.WithFields(logrus.Fields{
+				"elapsed": time.Since(start),
+				"status":  status,
+			}).Info("package build finished")
+
+			if err != nil {
+				mu.Lock()
+				failed[p.Name] = true
+				mu.Unlock()
+			}
+		}(p)
+	}
+
+	wg.Wait()
+
+	for _, f := range failed {
+		if f {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDuplicateNames reports an error if two packages share a name. The
+// scheduler in Build indexes its "done" and "failed" state by package
+// name, so a duplicate would make two goroutines share one done channel
+// and panic when both tried to close it.
+func (pkgr *Packager) checkDuplicateNames() error {
+	seen := make(map[string]bool, len(pkgr.Packages))
+
+	for _, p := range pkgr.Packages {
+		if seen[p.Name] {
+			return fmt.Errorf("%w: %s", ErrDuplicatePackageName, p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	return nil
+}
+
+// checkCycles walks the build-depends graph looking for a cycle, so Build
+// can fail fast instead of leaving goroutines waiting on each other
+// forever.
+func (pkgr *Packager) checkCycles() error {
+	const (
+		white = iota
+		gray
+		black
+	)
+
+	byName := make(map[string]*Package, len(pkgr.Packages))
+	for _, p := range pkgr.Packages {
+		byName[p.Name] = p
+	}
+
+	color := make(map[string]int, len(pkgr.Packages))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch color[name] {
+		case gray:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, name)
+		case black:
+			return nil
+		}
+
+		color[name] = gray
+		if p, ok := byName[name]; ok {
+			for _, dep := range p.BuildDepends {
+				if _, ok := byName[dep]; !ok {
+					continue
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		color[name] = black
+
+		return nil
+	}
+
+	for _, p := range pkgr.Packages {
+		if err := visit(p.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
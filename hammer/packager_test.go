@@ -0,0 +1,79 @@
+package hammer
+
+import (
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestCheckCyclesDetectsCycle(t *testing.T) {
+	pkgr := &Packager{
+		Packages: []*Package{
+			{Name: "a", BuildDepends: []string{"b"}},
+			{Name: "b", BuildDepends: []string{"a"}},
+		},
+		logger: newTestLogger(),
+	}
+
+	if err := pkgr.checkCycles(); err == nil {
+		t.Fatal("expected a cycle error, got nil")
+	}
+}
+
+func TestCheckCyclesAllowsValidOrdering(t *testing.T) {
+	pkgr := &Packager{
+		Packages: []*Package{
+			{Name: "a", BuildDepends: []string{"b"}},
+			{Name: "b", BuildDepends: []string{"c"}},
+			{Name: "c"},
+		},
+		logger: newTestLogger(),
+	}
+
+	if err := pkgr.checkCycles(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCheckCyclesIgnoresDependsOutsideTheSet(t *testing.T) {
+	pkgr := &Packager{
+		Packages: []*Package{
+			{Name: "a", BuildDepends: []string{"not-in-this-build"}},
+		},
+		logger: newTestLogger(),
+	}
+
+	if err := pkgr.checkCycles(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCheckDuplicateNamesDetectsDuplicates(t *testing.T) {
+	pkgr := &Packager{
+		Packages: []*Package{
+			{Name: "dup"},
+			{Name: "dup"},
+		},
+		logger: newTestLogger(),
+	}
+
+	if err := pkgr.checkDuplicateNames(); err == nil {
+		t.Fatal("expected a duplicate name error, got nil")
+	}
+}
+
+func TestBuildFailsCleanlyOnDuplicateNames(t *testing.T) {
+	pkgr := NewPackager([]*Package{
+		{Name: "dup"},
+		{Name: "dup"},
+	})
+	pkgr.Jobs = 2
+
+	if pkgr.Build() {
+		t.Fatal("expected Build to report failure for duplicate package names")
+	}
+}
+
+func newTestLogger() *logrus.Entry {
+	return logrus.WithField("component", "packager-test")
+}
@@ -0,0 +1,184 @@
+package hammer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrPluginManifestNotFound is returned when a candidate plugin directory
+// has no plugin.yaml in it.
+var ErrPluginManifestNotFound = errors.New("no plugin.yaml found in plugin directory")
+
+// PluginManifest describes one hammer plugin: a name and usage string for
+// its cobra subcommand, the command to exec, and which extra environment
+// variables to pass through to it.
+type PluginManifest struct {
+	Name    string   `yaml:"name"`
+	Usage   string   `yaml:"usage"`
+	Command string   `yaml:"command"`
+	Env     []string `yaml:"env"`
+
+	Dir string `yaml:"-"`
+}
+
+// PluginDirs returns every directory hammer scans for plugins:
+// $HAMMER_PLUGINS_DIR first, then each entry of the colon-separated
+// configDirs value.
+func PluginDirs(configDirs string) []string {
+	dirs := []string{}
+
+	if d := os.Getenv("HAMMER_PLUGINS_DIR"); d != "" {
+		dirs = append(dirs, d)
+	}
+
+	for _, d := range strings.Split(configDirs, ":") {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+
+	return dirs
+}
+
+// DiscoverPlugins loads every plugin.yaml found one level under dirs.
+// Directories that don't exist, or don't contain a plugin, are skipped
+// rather than treated as errors.
+func DiscoverPlugins(dirs []string) ([]*PluginManifest, error) {
+	manifests := []*PluginManifest{}
+
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			manifest, err := loadPluginManifest(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			manifests = append(manifests, manifest)
+		}
+	}
+
+	return manifests, nil
+}
+
+func loadPluginManifest(dir string) (*PluginManifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		return nil, ErrPluginManifestNotFound
+	}
+
+	manifest := new(PluginManifest)
+	if err := yaml.Unmarshal(content, manifest); err != nil {
+		return nil, err
+	}
+	manifest.Dir = dir
+
+	return manifest, nil
+}
+
+// Run execs the plugin's command, handing it the resolved package list as
+// JSON on stdin and HAMMER_OUTPUT/HAMMER_SEARCH, plus its declared Env
+// passthrough, in its environment.
+func (m *PluginManifest) Run(packages []*Package, output, search string, args []string) ([]byte, error) {
+	body, err := json.Marshal(packages)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(m.Command, args...)
+	cmd.Dir = m.Dir
+	cmd.Stdin = bytes.NewReader(body)
+
+	cmd.Env = []string{
+		"HAMMER_OUTPUT=" + output,
+		"HAMMER_SEARCH=" + search,
+	}
+	for _, name := range m.Env {
+		if val, ok := os.LookupEnv(name); ok {
+			cmd.Env = append(cmd.Env, name+"="+val)
+		}
+	}
+
+	return cmd.CombinedOutput()
+}
+
+// ErrInvalidPluginName is returned when a plugin name can't be used as a
+// single path component, e.g. because it contains a path separator or is
+// "..".
+var ErrInvalidPluginName = errors.New("invalid plugin name")
+
+// validatePluginName rejects anything that isn't safe to use as a single
+// path component under a plugins directory.
+func validatePluginName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("%w: %q", ErrInvalidPluginName, name)
+	}
+	return nil
+}
+
+// InstallPlugin copies a plugin directory (a plugin.yaml alongside
+// whatever it execs) into dest, for `hammer plugin install`.
+func InstallPlugin(src, dest string) error {
+	manifest, err := loadPluginManifest(src)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePluginName(manifest.Name); err != nil {
+		return err
+	}
+
+	target := filepath.Join(dest, manifest.Name)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		body, err := ioutil.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(target, entry.Name()), body, entry.Mode()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemovePlugin deletes a previously-installed plugin's directory from
+// dest, for `hammer plugin remove`.
+func RemovePlugin(dest, name string) error {
+	if err := validatePluginName(name); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(dest, name))
+}
@@ -0,0 +1,125 @@
+package hammer
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+
+	"github.com/asteris-llc/hammer/hammer/dlcache"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrChecksumMismatch is returned by Resource.Download when the body it
+// fetched does not match the checksum the package declared for it.
+var ErrChecksumMismatch = errors.New("downloaded resource does not match declared checksum")
+
+// ErrDownloadFailed is returned by Resource.Download when the server
+// responds with anything other than 2xx.
+var ErrDownloadFailed = errors.New("downloading resource failed")
+
+// skipChecksum lets a package opt out of verification for a resource it
+// trusts by other means (e.g. it's already signed, or pinned by URL only).
+const skipChecksum = "skip"
+
+// Resource is a single file a Package needs fetched before it can build,
+// optionally pinned to a checksum for reproducibility.
+type Resource struct {
+	URL     string `yaml:"url"`
+	SHA256  string `yaml:"sha256"`
+	SHA512  string `yaml:"sha512"`
+	Blake2b string `yaml:"blake2b"`
+}
+
+func (r *Resource) Name() string {
+	return path.Base(r.URL)
+}
+
+// checksum returns the strongest declared checksum, along with the name
+// of its algorithm; algo is "" if the resource declared none.
+func (r *Resource) checksum() (algo, sum string) {
+	switch {
+	case r.Blake2b != "":
+		return "blake2b", r.Blake2b
+	case r.SHA512 != "":
+		return "sha512", r.SHA512
+	case r.SHA256 != "":
+		return "sha256", r.SHA256
+	default:
+		return "", ""
+	}
+}
+
+func (r *Resource) verify(algo, want string, body []byte) error {
+	if algo == "" || want == skipChecksum {
+		return nil
+	}
+
+	var got string
+	switch algo {
+	case "blake2b":
+		sum := blake2b.Sum256(body)
+		got = hex.EncodeToString(sum[:])
+	case "sha512":
+		sum := sha512.Sum512(body)
+		got = hex.EncodeToString(sum[:])
+	case "sha256":
+		sum := sha256.Sum256(body)
+		got = hex.EncodeToString(sum[:])
+	}
+
+	if got != want {
+		return fmt.Errorf("%w: %s declared %s but downloaded %s", ErrChecksumMismatch, r.URL, want, got)
+	}
+
+	return nil
+}
+
+// Download fetches the resource's body, consulting and populating the
+// shared dlcache keyed on URL + declared checksum, and refusing to
+// return a body that fails checksum verification.
+func (r *Resource) Download(p *Package) ([]byte, error) {
+	algo, sum := r.checksum()
+
+	if sum != skipChecksum {
+		if body, ok, err := dlcache.Get(r.URL, sum); err != nil {
+			p.logger.WithField("error", err).Warn("could not read download cache")
+		} else if ok {
+			p.logger.WithField("url", r.URL).Debug("resource served from download cache")
+			return body, nil
+		}
+	}
+
+	p.logger.WithField("url", r.URL).Info("downloading resource")
+	resp, err := http.Get(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: %s returned %s", ErrDownloadFailed, r.URL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.verify(algo, sum, body); err != nil {
+		p.logger.WithField("error", err).Error("checksum verification failed")
+		return nil, err
+	}
+
+	if sum != skipChecksum {
+		if err := dlcache.Put(r.URL, sum, body); err != nil {
+			p.logger.WithField("error", err).Warn("could not write download cache")
+		}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,209 @@
+package hammer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// AllowedExecutables is the set of host binaries a sandboxed build script
+// may invoke. Anything else is rejected before it runs. A shell is
+// deliberately excluded: handing a script shell access to spawn another
+// shell would let it sidestep every other restriction here.
+var AllowedExecutables = []string{
+	"mkdir", "cp", "mv", "rm", "tar", "make", "install", "ln", "sed", "patch",
+}
+
+// sandboxEnv is the only environment a sandboxed build script sees. It
+// deliberately does not inherit the host process's environment, which
+// could otherwise leak credentials or host-specific paths into scripts.
+func sandboxEnv(buildRoot string) expand.Environ {
+	return expand.ListEnviron(
+		"PATH=/usr/bin:/bin",
+		"HOME="+buildRoot,
+		"TMPDIR="+buildRoot,
+		"BUILDROOT="+buildRoot,
+	)
+}
+
+var (
+	ErrExecutableNotAllowed = errors.New("executable is not in hammer's sandbox allow-list")
+	ErrPathEscapesBuildRoot = errors.New("path escapes the build root")
+)
+
+// runSandboxed parses and runs script with an embedded shell interpreter
+// confined to buildRoot: it can only exec AllowedExecutables (plus the
+// install-* builtins below) and can only read or write paths under
+// buildRoot.
+func runSandboxed(logger *logrus.Entry, script, buildRoot string) ([]byte, error) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "build")
+	if err != nil {
+		return []byte{}, err
+	}
+
+	var out bytes.Buffer
+	runner, err := interp.New(
+		interp.Dir(buildRoot),
+		interp.Env(sandboxEnv(buildRoot)),
+		interp.StdIO(nil, &out, &out),
+		interp.ExecHandler(sandboxExecHandler(buildRoot)),
+		interp.OpenHandler(sandboxOpenHandler(buildRoot)),
+	)
+	if err != nil {
+		return out.Bytes(), err
+	}
+
+	logger.WithField("buildRoot", buildRoot).Debug("running build script in sandbox")
+	err = runner.Run(context.Background(), file)
+
+	return out.Bytes(), err
+}
+
+// runHostShell is the legacy behavior, used when --unsafe is passed.
+func runHostShell(logger *logrus.Entry, script, buildRoot string) ([]byte, error) {
+	logger.Warn("running build script with the host shell (--unsafe)")
+
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = buildRoot
+
+	return cmd.CombinedOutput()
+}
+
+// sandboxExecHandler dispatches to a staging builtin when the command
+// name matches one, otherwise runs it through the default handler if it's
+// on AllowedExecutables and every path-shaped argument it was given stays
+// under buildRoot, otherwise refuses.
+func sandboxExecHandler(buildRoot string) interp.ExecHandlerFunc {
+	builtins := stagingBuiltins(buildRoot)
+	defaultHandler := interp.DefaultExecHandler(2 * time.Second)
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) == 0 {
+			return nil
+		}
+
+		if builtin, ok := builtins[args[0]]; ok {
+			return builtin(args[1:])
+		}
+
+		allowed := false
+		for _, name := range AllowedExecutables {
+			if name == args[0] {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", ErrExecutableNotAllowed, args[0])
+		}
+
+		if err := confineArgs(buildRoot, args[1:]); err != nil {
+			return err
+		}
+
+		return defaultHandler(ctx, args)
+	}
+}
+
+// confineArgs rejects any argument that resolves outside buildRoot, so an
+// allow-listed binary like `cp` can't be pointed at host files by an
+// absolute or ../-escaping argument. Every argument is checked, not just
+// ones that contain a "/" - a bare ".." is just as much an escape.
+func confineArgs(buildRoot string, args []string) error {
+	for _, arg := range args {
+		if _, err := sandboxPath(buildRoot, arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sandboxOpenHandler refuses any path that resolves outside buildRoot.
+func sandboxOpenHandler(buildRoot string) interp.OpenHandlerFunc {
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		if _, err := sandboxPath(buildRoot, path); err != nil {
+			return nil, err
+		}
+
+		return interp.DefaultOpenHandler()(ctx, path, flag, perm)
+	}
+}
+
+// stagingBuiltins are the extra commands package scripts get for copying
+// build output into the packaging tree with the right mode, mirroring
+// how other source-package build tools sandbox their build scripts.
+func stagingBuiltins(buildRoot string) map[string]func(args []string) error {
+	install := func(mode os.FileMode) func([]string) error {
+		return func(args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("expected exactly 2 arguments (src, dest), got %d", len(args))
+			}
+			return stageInto(buildRoot, args[0], args[1], mode)
+		}
+	}
+
+	return map[string]func(args []string) error{
+		"install-binary":       install(0755),
+		"install-file":         install(0644),
+		"install-manpage":      install(0644),
+		"install-systemd-unit": install(0644),
+	}
+}
+
+func stageInto(buildRoot, src, dest string, mode os.FileMode) error {
+	absSrc, err := sandboxPath(buildRoot, src)
+	if err != nil {
+		return err
+	}
+
+	absDest, err := sandboxPath(buildRoot, dest)
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadFile(absSrc)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absDest), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(absDest, body, mode)
+}
+
+// sandboxPath resolves p against buildRoot and rejects it if it escapes
+// buildRoot, whether p was relative or a suspiciously absolute path.
+func sandboxPath(buildRoot, p string) (string, error) {
+	abs := p
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(buildRoot, p)
+	}
+	abs = filepath.Clean(abs)
+
+	rootAbs, err := filepath.Abs(buildRoot)
+	if err != nil {
+		return "", err
+	}
+
+	if abs != rootAbs && !strings.HasPrefix(abs, rootAbs+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: %s", ErrPathEscapesBuildRoot, p)
+	}
+
+	return abs, nil
+}
@@ -0,0 +1,77 @@
+package hammer
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// hookNames are the maintainer-script hooks a Package may define besides
+// "build", i.e. fpm's install/remove/upgrade hooks.
+var hookNames = []string{
+	"before-install", "after-install",
+	"before-remove", "after-remove",
+	"before-upgrade", "after-upgrade",
+}
+
+// Scripts holds the named shell snippets a Package may define: "build"
+// plus fpm's before/after install/remove/upgrade hooks.
+type Scripts map[string]string
+
+// Unsafe makes BuildSources hand build scripts to the host shell instead
+// of hammer's sandboxed interpreter. It is set from BuildCmd's --unsafe
+// flag; leave it false unless a package genuinely needs something the
+// sandbox's allow-list doesn't permit.
+var Unsafe = false
+
+// BuildSources runs the package's "build" script rooted at buildRoot and
+// returns its combined output.
+func (s Scripts) BuildSources(logger *logrus.Entry, buildRoot string) ([]byte, error) {
+	script, ok := s["build"]
+	if !ok {
+		return []byte{}, nil
+	}
+
+	if Unsafe {
+		return runHostShell(logger, script, buildRoot)
+	}
+
+	return runSandboxed(logger, script, buildRoot)
+}
+
+// Hooks renders every maintainer-script hook the package defines
+// (everything but "build"), keyed by hook name, for backends that need to
+// embed them in the package they emit.
+func (s Scripts) Hooks(p *Package) (map[string]string, error) {
+	rendered := make(map[string]string, len(hookNames))
+
+	known := make(map[string]bool, len(hookNames)+1)
+	known["build"] = true
+
+	for _, name := range hookNames {
+		known[name] = true
+
+		content, ok := s[name]
+		if !ok {
+			continue
+		}
+
+		buf, err := p.Render(content)
+		if err != nil {
+			return nil, fmt.Errorf("rendering %s script: %w", name, err)
+		}
+
+		rendered[name] = buf.String()
+	}
+
+	// Unlike the fpm backend (see fpmArgs), the native backends only
+	// look for known hook names, so a typo here would otherwise be
+	// silently dropped instead of erroring.
+	for name := range s {
+		if !known[name] {
+			p.logger.WithField("script", name).Warn("ignoring unrecognized script name")
+		}
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/asteris-llc/hammer/hammer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var RootCmd = &cobra.Command{
+	Use:   "hammer",
+	Short: "build and package software",
+}
+
+func main() {
+	RootCmd.AddCommand(BuildCmd)
+	RootCmd.AddCommand(pluginCmd())
+
+	for _, manifest := range discoverPlugins() {
+		RootCmd.AddCommand(pluginSubcommand(manifest))
+	}
+
+	if err := RootCmd.Execute(); err != nil {
+		logrus.WithField("error", err).Fatal("command failed")
+	}
+}
+
+func init() {
+	RootCmd.PersistentFlags().String("search", ".", "directory to search for packages")
+	viper.BindPFlag("search", RootCmd.PersistentFlags().Lookup("search"))
+
+	RootCmd.PersistentFlags().String("output", "output", "directory to write built packages to")
+	viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
+
+	RootCmd.PersistentFlags().String("plugin-dirs", "", "colon-separated list of extra plugin directories")
+	viper.BindPFlag("plugin-dirs", RootCmd.PersistentFlags().Lookup("plugin-dirs"))
+}
+
+func discoverPlugins() []*hammer.PluginManifest {
+	dirs := hammer.PluginDirs(viper.GetString("plugin-dirs"))
+
+	manifests, err := hammer.DiscoverPlugins(dirs)
+	if err != nil {
+		logrus.WithField("error", err).Warn("could not discover plugins")
+	}
+
+	return manifests
+}
+
+// pluginSubcommand wraps a discovered plugin manifest as a cobra command
+// that hands it HAMMER_OUTPUT/HAMMER_SEARCH and the resolved package list
+// on stdin as JSON.
+func pluginSubcommand(manifest *hammer.PluginManifest) *cobra.Command {
+	return &cobra.Command{
+		Use:   manifest.Name,
+		Short: manifest.Usage,
+		Run: func(cmd *cobra.Command, args []string) {
+			loader := hammer.NewLoader(viper.GetString("search"))
+			packages, err := loader.Load()
+			if err != nil {
+				logrus.WithField("error", err).Fatal("could not load packages")
+			}
+
+			out, err := manifest.Run(packages, viper.GetString("output"), viper.GetString("search"), args)
+			fmt.Print(string(out))
+			if err != nil {
+				logrus.WithField("error", err).Fatal("plugin failed")
+			}
+		},
+	}
+}
+
+// pluginCmd is the `hammer plugin` command group for managing the primary
+// plugin directory.
+func pluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "manage hammer plugins",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "list installed plugins",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, manifest := range discoverPlugins() {
+				fmt.Printf("%s\t%s\n", manifest.Name, manifest.Usage)
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "install [path]",
+		Short: "install a plugin from a local directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				logrus.Fatal("usage: hammer plugin install [path]")
+			}
+
+			if err := hammer.InstallPlugin(args[0], primaryPluginDir()); err != nil {
+				logrus.WithField("error", err).Fatal("could not install plugin")
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove [name]",
+		Short: "remove an installed plugin",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				logrus.Fatal("usage: hammer plugin remove [name]")
+			}
+
+			if err := hammer.RemovePlugin(primaryPluginDir(), args[0]); err != nil {
+				logrus.WithField("error", err).Fatal("could not remove plugin")
+			}
+		},
+	})
+
+	return cmd
+}
+
+// primaryPluginDir is where `hammer plugin install/remove` manage
+// plugins, defaulting to $HAMMER_PLUGINS_DIR and falling back to
+// ~/.hammer/plugins.
+func primaryPluginDir() string {
+	if d := os.Getenv("HAMMER_PLUGINS_DIR"); d != "" {
+		return d
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".hammer/plugins"
+	}
+
+	return home + "/.hammer/plugins"
+}